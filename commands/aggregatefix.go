@@ -0,0 +1,419 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jfrog/frogbot/commands/utils"
+	"github.com/jfrog/froggit-go/vcsclient"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+)
+
+// DependencyGraph flattens a dependency tree into impacted-package -> resolved-version pairs.
+type DependencyGraph map[string]string
+
+// conflictMatrix models the aggregated-fix search space: one row per impacted package, whose columns are its
+// remaining candidate fix versions, alongside each candidate's transitive requirement set.
+type conflictMatrix struct {
+	packages     []string                              // stable row order
+	domains      map[string][]string                   // impacted package -> remaining candidate versions
+	requirements map[string]map[string]DependencyGraph // impacted package -> version -> transitive requirements
+}
+
+// tryFixAggregatedPackagesAndCreatePR attempts to resolve every impacted package's fix version at once. If a
+// consistent assignment exists it opens a single combined PR and reports aggregated=true. Otherwise it records the
+// conflict on cfp.aggregationConflictNote, so the caller's per-package fallback loop can annotate its PRs, and
+// reports aggregated=false.
+func (cfp *CreateFixPullRequestsCmd) tryFixAggregatedPackagesAndCreatePR(fixVersionsMap map[string]*FixVersionInfo, params *utils.FrogbotParams, client vcsclient.VcsClient, gitManager *utils.GitManager) (aggregated bool, err error) {
+	cfp.getLogger().Info("Aggregated-fix mode is on, building the transitive dependency conflict matrix")
+	matrix, err := buildConflictMatrix(cfp, fixVersionsMap, params, gitManager)
+	if err != nil {
+		// Building the matrix shells out to each technology's dependency dry-run per candidate; a failure there
+		// (a missing tool, a network hiccup, a candidate that doesn't even resolve) shouldn't abort the whole run -
+		// fall back to the per-package loop, the same way an unsatisfiable matrix does below.
+		cfp.getLogger().Warn("Failed building the transitive dependency conflict matrix:", err.Error(), "- falling back to one PR per package")
+		cfp.aggregationConflictNote = "the transitive dependency conflict matrix could not be built: " + err.Error()
+		// buildConflictMatrix only reverts the in-progress candidate's manifest changes after each successful
+		// iteration, so a failure mid-loop leaves the working tree dirty with a half-applied candidate version.
+		// gitManager.Checkout is a branch-level checkout, a no-op on file state when we're already on that branch
+		// (we never switched branches here) - discard the half-applied changes the same way buildConflictMatrix
+		// does between candidates instead.
+		if _, restoreErr := exec.Command("git", "checkout", "--", ".").CombinedOutput(); restoreErr != nil { // #nosec G204
+			return false, restoreErr
+		}
+		return false, nil
+	}
+	if err = gitManager.Checkout(params.BaseBranch); err != nil {
+		return false, err
+	}
+
+	assignment, ok, conflict := solveConflictMatrix(matrix)
+	if !ok {
+		cfp.getLogger().Info("Aggregated fix isn't possible:", conflict, "- falling back to one PR per package")
+		cfp.aggregationConflictNote = conflict
+		return false, nil
+	}
+
+	cfp.getLogger().Info("Found a consistent fix version assignment for all", len(assignment), "impacted packages")
+	if err = cfp.fixAggregatedPackagesAndCreatePR(assignment, fixVersionsMap, params, client, gitManager); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// buildConflictMatrix runs the impacted technology's dependency dry-run once per candidate fix version of every
+// impacted package, recording what each candidate would transitively require, then reverts the working tree to a
+// clean baseline before evaluating the next candidate.
+func buildConflictMatrix(cfp *CreateFixPullRequestsCmd, fixVersionsMap map[string]*FixVersionInfo, params *utils.FrogbotParams, gitManager *utils.GitManager) (*conflictMatrix, error) {
+	matrix := &conflictMatrix{domains: map[string][]string{}, requirements: map[string]map[string]DependencyGraph{}}
+	for impactedPackage, fixVersionInfo := range fixVersionsMap {
+		matrix.packages = append(matrix.packages, impactedPackage)
+		candidates := sortedUniqueVersions(fixVersionInfo.candidates, fixVersionInfo.packageType)
+		matrix.domains[impactedPackage] = candidates
+		matrix.requirements[impactedPackage] = map[string]DependencyGraph{}
+		for _, candidate := range candidates {
+			if err := cfp.updatePackageToFixedVersion(fixVersionInfo.packageType, impactedPackage, candidate, params.RequirementsFile); err != nil {
+				return nil, err
+			}
+			graph, err := fetchTransitiveRequirements(fixVersionInfo.packageType)
+			if err != nil {
+				return nil, err
+			}
+			matrix.requirements[impactedPackage][candidate] = graph
+			// Revert the working tree so the next candidate is evaluated from a clean baseline.
+			if _, err = exec.Command("git", "checkout", "--", ".").CombinedOutput(); err != nil { // #nosec G204
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(matrix.packages)
+	return matrix, nil
+}
+
+// solveConflictMatrix prunes the matrix with propagateConstraints and then backtracks over what's left to find an
+// assignment of one fix version per impacted package such that no two packages require conflicting versions of any
+// shared transitive dependency.
+func solveConflictMatrix(matrix *conflictMatrix) (assignment map[string]string, ok bool, conflict string) {
+	propagateConstraints(matrix)
+	for _, impactedPackage := range matrix.packages {
+		if len(matrix.domains[impactedPackage]) == 0 {
+			return nil, false, fmt.Sprintf("no fix version of %s satisfies the other packages' transitive requirements", impactedPackage)
+		}
+	}
+
+	assignment = map[string]string{}
+	if !backtrack(matrix, 0, assignment, DependencyGraph{}) {
+		return nil, false, "no combination of fix versions satisfies every impacted package's transitive requirements simultaneously"
+	}
+	return assignment, true, ""
+}
+
+// propagateConstraints repeatedly removes candidates that would pin a shared transitive package to two different
+// versions at once, whenever another row's domain has already narrowed to a single candidate. It iterates to a
+// fixed point, mirroring how constraint-propagation dependency resolvers prune a candidate matrix before searching
+// it, instead of backtracking over the full, unpruned search space.
+func propagateConstraints(matrix *conflictMatrix) {
+	for {
+		changed := false
+		for _, fixedPackage := range matrix.packages {
+			domain := matrix.domains[fixedPackage]
+			if len(domain) != 1 {
+				continue
+			}
+			fixedRequirements := matrix.requirements[fixedPackage][domain[0]]
+			for _, otherPackage := range matrix.packages {
+				if otherPackage == fixedPackage {
+					continue
+				}
+				var kept []string
+				for _, candidate := range matrix.domains[otherPackage] {
+					if requirementsConflict(fixedRequirements, matrix.requirements[otherPackage][candidate]) {
+						changed = true
+						continue
+					}
+					kept = append(kept, candidate)
+				}
+				matrix.domains[otherPackage] = kept
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+// backtrack tries every remaining candidate for the package at matrix.packages[index], accumulating the combined
+// transitive requirement set already chosen in assigned, and recurses into the next row. It returns false once a
+// row has no candidate compatible with what's already been assigned.
+func backtrack(matrix *conflictMatrix, index int, assignment map[string]string, assigned DependencyGraph) bool {
+	if index == len(matrix.packages) {
+		return true
+	}
+	impactedPackage := matrix.packages[index]
+	for _, candidate := range matrix.domains[impactedPackage] {
+		requirements := matrix.requirements[impactedPackage][candidate]
+		if requirementsConflict(assigned, requirements) {
+			continue
+		}
+		assignment[impactedPackage] = candidate
+		if backtrack(matrix, index+1, assignment, mergeRequirements(assigned, requirements)) {
+			return true
+		}
+		delete(assignment, impactedPackage)
+	}
+	return false
+}
+
+// requirementsConflict reports whether a and b both pin the same transitive package to different versions.
+func requirementsConflict(a, b DependencyGraph) bool {
+	for pkg, requiredVersion := range a {
+		if otherVersion, exists := b[pkg]; exists && otherVersion != requiredVersion {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeRequirements(a, b DependencyGraph) DependencyGraph {
+	merged := make(DependencyGraph, len(a)+len(b))
+	for pkg, requiredVersion := range a {
+		merged[pkg] = requiredVersion
+	}
+	for pkg, requiredVersion := range b {
+		merged[pkg] = requiredVersion
+	}
+	return merged
+}
+
+// fixAggregatedPackagesAndCreatePR applies every fix version in assignment on a single branch and opens one PR
+// whose body lists every CVE the combined upgrade addresses.
+func (cfp *CreateFixPullRequestsCmd) fixAggregatedPackagesAndCreatePR(assignment map[string]string, fixVersionsMap map[string]*FixVersionInfo, params *utils.FrogbotParams, client vcsclient.VcsClient, gitManager *utils.GitManager) (err error) {
+	startTime := time.Now()
+	baseEvent := utils.Event{}
+	defer func() {
+		if err != nil {
+			failedEvent := baseEvent
+			failedEvent.DurationMs = time.Since(startTime).Milliseconds()
+			cfp.emitEvent("pr_failed", failedEvent)
+		}
+	}()
+
+	fixBranchName, err := generateAggregatedFixBranchName(params.BaseBranch, assignment)
+	if err != nil {
+		return err
+	}
+	baseEvent.Branch = fixBranchName
+
+	exists, err := gitManager.BranchExistsOnRemote(fixBranchName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		cfp.getLogger().Info("Branch:", fixBranchName, "already exists on remote.")
+		return nil
+	}
+	cfp.getLogger().Info("Creating aggregated fix branch:", fixBranchName)
+	if err = gitManager.CreateBranchAndCheckout(fixBranchName); err != nil {
+		return err
+	}
+	cfp.emitEvent("branch_created", withDuration(baseEvent, startTime))
+
+	packages := make([]string, 0, len(assignment))
+	for impactedPackage := range assignment {
+		packages = append(packages, impactedPackage)
+	}
+	sort.Strings(packages)
+
+	var summaryLines []string
+	var cveIds []string
+	for _, impactedPackage := range packages {
+		fixVersion := assignment[impactedPackage]
+		fixVersionInfo := fixVersionsMap[impactedPackage]
+		if err = cfp.updatePackageToFixedVersion(fixVersionInfo.packageType, impactedPackage, fixVersion, params.RequirementsFile); err != nil {
+			return err
+		}
+		summaryLines = append(summaryLines, fmt.Sprintf("- %s -> %s", impactedPackage, fixVersion))
+		cveIds = append(cveIds, fixVersionInfo.cveIds...)
+	}
+	baseEvent.CveIds = dedupeStrings(cveIds)
+	cfp.emitEvent("manifest_updated", withDuration(baseEvent, startTime))
+
+	cfp.getLogger().Info("Checking if there are changes to commit")
+	isClean, err := gitManager.IsClean()
+	if err != nil {
+		return err
+	}
+	if isClean {
+		return fmt.Errorf("there were no changes to commit after applying the aggregated fix")
+	}
+
+	commitString := fmt.Sprintf("[🐸 Frogbot] Aggregated upgrade of %d vulnerable dependencies", len(assignment))
+	cfp.getLogger().Info("Running git add all and commit")
+	if err = gitManager.AddAllAndCommit(commitString); err != nil {
+		return err
+	}
+	cfp.getLogger().Info("Pushing fix branch:", fixBranchName)
+	if err = gitManager.Push(); err != nil {
+		return err
+	}
+	cfp.emitEvent("commit_pushed", withDuration(baseEvent, startTime))
+
+	cfp.getLogger().Info("Creating Pull Request form:", fixBranchName, " to:", params.BaseBranch)
+	prBody := commitString + "\n\n" + strings.Join(summaryLines, "\n") + "\n\nCVEs fixed: " + strings.Join(baseEvent.CveIds, ", ") + "\n\n" + utils.WhatIsFrogbotMd
+	if err = client.CreatePullRequest(context.Background(), params.RepoOwner, params.Repo, fixBranchName, params.BaseBranch, commitString, prBody); err != nil {
+		return err
+	}
+	cfp.emitEvent("pr_opened", withDuration(baseEvent, startTime))
+	return nil
+}
+
+// generateAggregatedFixBranchName derives a stable branch name from every package/version pair in the assignment,
+// the same way generateFixBranchName does for a single package.
+func generateAggregatedFixBranchName(baseBranch string, assignment map[string]string) (string, error) {
+	packages := make([]string, 0, len(assignment))
+	for impactedPackage := range assignment {
+		packages = append(packages, impactedPackage)
+	}
+	sort.Strings(packages)
+	parts := make([]string, 0, len(packages)+1)
+	parts = append(parts, "frogbot", baseBranch)
+	for _, impactedPackage := range packages {
+		parts = append(parts, fmt.Sprintf("%s-%s", impactedPackage, assignment[impactedPackage]))
+	}
+	uniqueString, err := utils.Md5Hash(parts...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%s", "frogbot", "aggregated", uniqueString), nil
+}
+
+func dedupeStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		unique = append(unique, v)
+	}
+	return unique
+}
+
+// fetchTransitiveRequirements invokes the package manager for tech in dry-run/list mode against the current
+// working directory, and returns a flattened package -> resolved-version dependency graph.
+func fetchTransitiveRequirements(tech coreutils.Technology) (DependencyGraph, error) {
+	switch tech {
+	case coreutils.Go:
+		return goModGraph()
+	case coreutils.Npm, coreutils.Yarn:
+		return npmLsGraph()
+	case coreutils.Maven:
+		return mavenDependencyTreeGraph()
+	case coreutils.Pip, coreutils.Pipenv:
+		return pipDepTreeGraph()
+	default:
+		return nil, fmt.Errorf("aggregated-fix dependency resolution isn't supported for technology: %s", string(tech))
+	}
+}
+
+func goModGraph() (DependencyGraph, error) {
+	output, err := exec.Command("go", "mod", "graph").CombinedOutput() // #nosec G204
+	if err != nil {
+		return nil, fmt.Errorf("go mod graph failed: %s\n%s", err.Error(), output)
+	}
+	graph := DependencyGraph{}
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		dep := strings.SplitN(fields[1], "@", 2)
+		if len(dep) == 2 {
+			graph[dep[0]] = dep[1]
+		}
+	}
+	return graph, scanner.Err()
+}
+
+type npmDependencyNode struct {
+	Version      string                       `json:"version"`
+	Dependencies map[string]npmDependencyNode `json:"dependencies"`
+}
+
+func npmLsGraph() (DependencyGraph, error) {
+	// npm ls exits non-zero on peer-dependency conflicts even though it still prints usable JSON, so only the
+	// parse error below is treated as fatal.
+	output, runErr := exec.Command("npm", "ls", "--all", "--json").CombinedOutput() // #nosec G204
+	var tree struct {
+		Dependencies map[string]npmDependencyNode `json:"dependencies"`
+	}
+	if err := json.Unmarshal(output, &tree); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("npm ls failed: %s\n%s", runErr.Error(), output)
+		}
+		return nil, err
+	}
+	graph := DependencyGraph{}
+	flattenNpmTree(tree.Dependencies, graph)
+	return graph, nil
+}
+
+func flattenNpmTree(dependencies map[string]npmDependencyNode, graph DependencyGraph) {
+	for name, node := range dependencies {
+		graph[name] = node.Version
+		if len(node.Dependencies) > 0 {
+			flattenNpmTree(node.Dependencies, graph)
+		}
+	}
+}
+
+// mavenCoordinateRegex matches a single dependency line of 'mvn dependency:tree -DoutputType=text', e.g.
+// "+- com.fasterxml.jackson.core:jackson-databind:jar:2.12.3:compile"
+var mavenCoordinateRegex = regexp.MustCompile(`([\w.-]+):([\w.-]+):[\w-]+:([\w.-]+):`)
+
+func mavenDependencyTreeGraph() (DependencyGraph, error) {
+	output, err := exec.Command("mvn", "-B", "dependency:tree", "-DoutputType=text").CombinedOutput() // #nosec G204
+	if err != nil {
+		return nil, fmt.Errorf("mvn dependency:tree failed: %s\n%s", err.Error(), output)
+	}
+	graph := DependencyGraph{}
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		match := mavenCoordinateRegex.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		graph[match[1]+":"+match[2]] = match[3]
+	}
+	return graph, scanner.Err()
+}
+
+func pipDepTreeGraph() (DependencyGraph, error) {
+	output, err := exec.Command("pipdeptree", "--json").CombinedOutput() // #nosec G204
+	if err != nil {
+		return nil, fmt.Errorf("pipdeptree failed: %s\n%s", err.Error(), output)
+	}
+	var tree []struct {
+		Package struct {
+			Key              string `json:"key"`
+			InstalledVersion string `json:"installed_version"`
+		} `json:"package"`
+	}
+	if err = json.Unmarshal(output, &tree); err != nil {
+		return nil, err
+	}
+	graph := DependencyGraph{}
+	for _, entry := range tree {
+		graph[entry.Package.Key] = entry.Package.InstalledVersion
+	}
+	return graph, nil
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveConflictMatrix_ConsistentAssignment(t *testing.T) {
+	// package A requires L@2 at both candidates, package B only satisfies L@2 at its higher candidate.
+	matrix := &conflictMatrix{
+		packages: []string{"A", "B"},
+		domains: map[string][]string{
+			"A": {"1.0.0", "1.1.0"},
+			"B": {"2.0.0", "2.1.0"},
+		},
+		requirements: map[string]map[string]DependencyGraph{
+			"A": {
+				"1.0.0": {"L": "2"},
+				"1.1.0": {"L": "2"},
+			},
+			"B": {
+				"2.0.0": {"L": "1"},
+				"2.1.0": {"L": "2"},
+			},
+		},
+	}
+
+	assignment, ok, conflict := solveConflictMatrix(matrix)
+	assert.True(t, ok)
+	assert.Empty(t, conflict)
+	assert.Equal(t, "2.1.0", assignment["B"])
+	assert.Contains(t, []string{"1.0.0", "1.1.0"}, assignment["A"])
+}
+
+func TestSolveConflictMatrix_NoConsistentAssignment(t *testing.T) {
+	matrix := &conflictMatrix{
+		packages: []string{"A", "B"},
+		domains: map[string][]string{
+			"A": {"1.0.0"},
+			"B": {"2.0.0"},
+		},
+		requirements: map[string]map[string]DependencyGraph{
+			"A": {"1.0.0": {"L": "2"}},
+			"B": {"2.0.0": {"L": "1"}},
+		},
+	}
+
+	assignment, ok, conflict := solveConflictMatrix(matrix)
+	assert.False(t, ok)
+	assert.Nil(t, assignment)
+	assert.NotEmpty(t, conflict)
+}
+
+func TestRequirementsConflict(t *testing.T) {
+	assert.True(t, requirementsConflict(DependencyGraph{"L": "1"}, DependencyGraph{"L": "2"}))
+	assert.False(t, requirementsConflict(DependencyGraph{"L": "1"}, DependencyGraph{"L": "1"}))
+	assert.False(t, requirementsConflict(DependencyGraph{"L": "1"}, DependencyGraph{"M": "2"}))
+}
+
+func TestDedupeStrings(t *testing.T) {
+	assert.Equal(t, []string{"CVE-1", "CVE-2"}, dedupeStrings([]string{"CVE-1", "", "CVE-2", "CVE-1"}))
+}
+
+func TestGenerateAggregatedFixBranchName(t *testing.T) {
+	name, err := generateAggregatedFixBranchName("main", map[string]string{"a": "1.0.0", "b": "2.0.0"})
+	assert.NoError(t, err)
+	assert.Contains(t, name, "frogbot-aggregated-")
+}
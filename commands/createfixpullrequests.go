@@ -4,21 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/jfrog/gofrog/version"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jfrog/frogbot/commands/utils"
 	"github.com/jfrog/froggit-go/vcsclient"
 	"github.com/jfrog/jfrog-cli-core/v2/xray/formats"
 	xrayutils "github.com/jfrog/jfrog-cli-core/v2/xray/utils"
 	"github.com/jfrog/jfrog-client-go/utils/io/fileutils"
-	clientLog "github.com/jfrog/jfrog-client-go/utils/log"
 	"github.com/jfrog/jfrog-client-go/xray/services"
+	"github.com/sirupsen/logrus"
 )
 
 // Package names are case-insensitive with this prefix
@@ -27,11 +29,35 @@ var pythonPackageRegexPrefix = "(?i)"
 // Match all possible operators and versions syntax
 var pythonPackageRegexSuffix = "\\s*(([\\=\\<\\>\\~]=)|([\\>\\<]))\\s*(\\.|\\d)*(\\d|(\\.\\*))(\\,\\s*(([\\=\\<\\>\\~]=)|([\\>\\<])).*\\s*(\\.|\\d)*(\\d|(\\.\\*)))?"
 
+// Gem names are case-insensitive with this prefix
+var rubyPackageRegexPrefix = "(?i)(gem\\s+['\"])"
+
+// Match the gem name followed by zero or more comma-separated version requirements,
+// e.g. gem 'rails', '~> 6.1.0' or gem "nokogiri", ">= 1.11.0", "< 2.0"
+var rubyPackageRegexSuffix = "(['\"]\\s*(,\\s*['\"][^'\"]+['\"]\\s*)*)"
+
+// rubyBundlerTechnology identifies the Ruby/Bundler ecosystem. jfrog-cli-core's coreutils.Technology enum has no
+// Ruby constant, so the Bundler-specific code paths below key off this locally-defined value instead.
+var rubyBundlerTechnology = coreutils.Technology("bundler")
+
 type CreateFixPullRequestsCmd struct {
 	mavenDepToPropertyMap map[string][]string
+	// aggregationConflictNote is set when utils.AggregateFixesEnabled is on but no single combination of fix versions
+	// satisfies every impacted package's transitive requirements, or when the transitive dependency conflict matrix
+	// could not be built at all. It gets surfaced in every per-package PR body
+	// that's opened as a result of the aggregated-fix fallback.
+	aggregationConflictNote string
+	// logger renders events in the format selected by utils.LogFormatEnvVar (text or JSON).
+	logger *logrus.Logger
+	// eventEmitter forwards every fix-pipeline event to the sinks configured through the environment, in addition
+	// to the logger.
+	eventEmitter *utils.EventEmitter
 }
 
 func (cfp CreateFixPullRequestsCmd) Run(params *utils.FrogbotParams, client vcsclient.VcsClient) error {
+	cfp.logger = utils.NewStructuredLogger()
+	cfp.eventEmitter = utils.NewEventEmitterFromEnv()
+
 	// Scan the current Branch
 	scanResults, err := cfp.scan(params)
 	if err != nil {
@@ -56,7 +82,7 @@ func (cfp *CreateFixPullRequestsCmd) scan(params *utils.FrogbotParams) ([]servic
 	if err != nil {
 		return nil, err
 	}
-	clientLog.Info("Xray scan completed")
+	cfp.getLogger().Info("Xray scan completed")
 	return scanResults, nil
 }
 
@@ -67,10 +93,10 @@ func (cfp *CreateFixPullRequestsCmd) fixImpactedPackagesAndCreatePRs(params *uti
 	}
 	// Nothing to fix, return
 	if len(fixVersionsMap) == 0 {
-		clientLog.Info("Didn't find vulnerable dependencies with existing fix versions")
+		cfp.getLogger().Info("Didn't find vulnerable dependencies with existing fix versions")
 		return nil
 	}
-	clientLog.Info("Found", len(fixVersionsMap), "vulnerable dependencies with fix versions")
+	cfp.getLogger().Info("Found", len(fixVersionsMap), "vulnerable dependencies with fix versions")
 
 	// Create temp working directory
 	wd, err := fileutils.CreateTempDir()
@@ -83,7 +109,7 @@ func (cfp *CreateFixPullRequestsCmd) fixImpactedPackagesAndCreatePRs(params *uti
 			err = e
 		}
 	}()
-	clientLog.Debug("Created temp working directory:", wd)
+	cfp.getLogger().Debug("Created temp working directory:", wd)
 
 	// Clone the content of the repo to the new working directory
 	gitManager, err := utils.NewGitManager(".", "origin", params.Token)
@@ -106,16 +132,28 @@ func (cfp *CreateFixPullRequestsCmd) fixImpactedPackagesAndCreatePRs(params *uti
 		}
 	}()
 
+	// In aggregated-fix mode, try to resolve every impacted package's fix version at once so that a single PR can
+	// close out the whole advisory batch instead of opening one PR per package.
+	if utils.AggregateFixesEnabled() {
+		aggregated, err := cfp.tryFixAggregatedPackagesAndCreatePR(fixVersionsMap, params, client, gitManager)
+		if err != nil {
+			return err
+		}
+		if aggregated {
+			return nil
+		}
+	}
+
 	// Fix all impacted packages
 	for impactedPackage, fixVersionInfo := range fixVersionsMap {
-		clientLog.Info("-----------------------------------------------------------------")
-		clientLog.Info("Start fixing", impactedPackage, "with", fixVersionInfo.fixVersion)
+		cfp.getLogger().Info("-----------------------------------------------------------------")
+		cfp.getLogger().Info("Start fixing", impactedPackage, "with", fixVersionInfo.fixVersion)
 		err = cfp.fixSinglePackageAndCreatePR(impactedPackage, *fixVersionInfo, params, client, gitManager)
 		if err != nil {
-			clientLog.Error("failed while trying to fix and create PR for:", impactedPackage, "with version:", fixVersionInfo.fixVersion, "with error:", err.Error())
+			cfp.getLogger().Error("failed while trying to fix and create PR for:", impactedPackage, "with version:", fixVersionInfo.fixVersion, "with error:", err.Error())
 		}
 		// After finishing to work on the current vulnerability we go back to the base branch to start the next vulnerability fix
-		clientLog.Info("Running git checkout to base branch:", params.BaseBranch)
+		cfp.getLogger().Info("Running git checkout to base branch:", params.BaseBranch)
 		err = gitManager.Checkout(params.BaseBranch)
 		if err != nil {
 			return err
@@ -143,16 +181,36 @@ func (cfp *CreateFixPullRequestsCmd) createFixVersionsMap(params *utils.FrogbotP
 					if !fixVulnerability {
 						continue
 					}
-					// Get the minimal fix version that fixes the current vulnerability. vulnerability.FixedVersions array is sorted, so we take the first index.
-					vulnFixVersion := parseVersionChangeString(vulnerability.FixedVersions[0])
+					// Resolve the actual affected-version ranges reported by Xray into candidate fix versions, and
+					// select the smallest one that is newer than what's currently installed.
+					ranges, err := parseFixedVersionRanges(vulnerability.FixedVersions)
+					if err != nil {
+						return nil, err
+					}
+					constraint := extractManifestConstraint(vulnerability.Technology, vulnerability.ImpactedPackageName, params.RequirementsFile)
+					vulnFixVersion, err := SelectFixVersion(ranges, vulnerability.ImpactedDependencyVersion, constraint, vulnerability.Technology)
+					if err != nil {
+						cfp.getLogger().Debug("Skipping", vulnerability.ImpactedPackageName, "-", err.Error())
+						continue
+					}
+					// Keep every actionable candidate from this vulnerability's ranges, not just the one selected,
+					// so validateFixVersion has real fallbacks to try if the selected version turns out to be yanked.
+					candidates, err := candidateFixVersions(ranges, vulnerability.ImpactedDependencyVersion, constraint, vulnerability.Technology)
+					if err != nil {
+						return nil, err
+					}
+					cveIds := extractCveIds(vulnerability)
 					fixVersionInfo, exists := fixVersionsMap[vulnerability.ImpactedPackageName]
 					if exists {
 						// More than one vulnerability can exist on the same impacted package.
 						// Among all possible fix versions that fix the above impacted package, we select the maximum fix version.
-						fixVersionInfo.UpdateFixVersion(vulnFixVersion)
+						if err = fixVersionInfo.UpdateFixVersion(vulnFixVersion, candidates); err != nil {
+							return nil, err
+						}
+						fixVersionInfo.cveIds = append(fixVersionInfo.cveIds, cveIds...)
 					} else {
 						// First appearance of a version that fixes the current impacted package
-						fixVersionsMap[vulnerability.ImpactedPackageName] = NewFixVersionInfo(vulnFixVersion, vulnerability.Technology)
+						fixVersionsMap[vulnerability.ImpactedPackageName] = NewFixVersionInfo(vulnFixVersion, candidates, vulnerability.Technology, vulnerability.ImpactedDependencyVersion, cveIds)
 					}
 				}
 			}
@@ -161,6 +219,24 @@ func (cfp *CreateFixPullRequestsCmd) createFixVersionsMap(params *utils.FrogbotP
 	return fixVersionsMap, nil
 }
 
+// extractCveIds returns the CVE identifiers attached to vulnerability, falling back to its Xray issue ID when no
+// CVE was assigned yet.
+func extractCveIds(vulnerability formats.VulnerabilityOrViolationRow) []string {
+	if len(vulnerability.Cves) == 0 {
+		if vulnerability.IssueId == "" {
+			return nil
+		}
+		return []string{vulnerability.IssueId}
+	}
+	cveIds := make([]string, 0, len(vulnerability.Cves))
+	for _, cve := range vulnerability.Cves {
+		if cve.Id != "" {
+			cveIds = append(cveIds, cve.Id)
+		}
+	}
+	return cveIds
+}
+
 func (cfp *CreateFixPullRequestsCmd) shouldFixVulnerability(params *utils.FrogbotParams, vulnerability formats.VulnerabilityOrViolationRow) (bool, error) {
 	// In Maven, fix only direct dependencies
 	if vulnerability.Technology == coreutils.Maven {
@@ -179,31 +255,57 @@ func (cfp *CreateFixPullRequestsCmd) shouldFixVulnerability(params *utils.Frogbo
 }
 
 func (cfp *CreateFixPullRequestsCmd) fixSinglePackageAndCreatePR(impactedPackage string, fixVersionInfo FixVersionInfo, params *utils.FrogbotParams, client vcsclient.VcsClient, gitManager *utils.GitManager) (err error) {
+	startTime := time.Now()
+	baseEvent := utils.Event{Package: impactedPackage, CurrentVersion: fixVersionInfo.currentVersion, FixVersion: fixVersionInfo.fixVersion, Technology: string(fixVersionInfo.packageType), CveIds: fixVersionInfo.cveIds}
+	defer func() {
+		if err != nil {
+			failedEvent := baseEvent
+			failedEvent.DurationMs = time.Since(startTime).Milliseconds()
+			cfp.emitEvent("pr_failed", failedEvent)
+		}
+	}()
+
+	registryClient, regErr := utils.NewRegistryClient(fixVersionInfo.packageType)
+	if regErr != nil {
+		cfp.getLogger().Debug("Skipping upstream verification for", impactedPackage, "-", regErr.Error())
+	} else {
+		cfp.getLogger().Info("Verifying fix version", fixVersionInfo.fixVersion, "for", impactedPackage, "exists upstream")
+		verifiedFixVersion, verifyErr := validateFixVersion(cfp.getLogger(), registryClient, impactedPackage, fixVersionInfo)
+		if verifyErr != nil {
+			return verifyErr
+		}
+		fixVersionInfo.fixVersion = verifiedFixVersion
+		baseEvent.FixVersion = verifiedFixVersion
+	}
+
 	fixBranchName, err := generateFixBranchName(params.BaseBranch, impactedPackage, fixVersionInfo.fixVersion)
 	if err != nil {
 		return err
 	}
+	baseEvent.Branch = fixBranchName
 
 	exists, err := gitManager.BranchExistsOnRemote(fixBranchName)
 	if err != nil {
 		return err
 	}
 	if exists {
-		clientLog.Info("Branch:", fixBranchName, "already exists on remote.")
+		cfp.getLogger().Info("Branch:", fixBranchName, "already exists on remote.")
 		return
 	}
-	clientLog.Info("Creating branch:", fixBranchName)
+	cfp.getLogger().Info("Creating branch:", fixBranchName)
 	err = gitManager.CreateBranchAndCheckout(fixBranchName)
 	if err != nil {
 		return err
 	}
+	cfp.emitEvent("branch_created", withDuration(baseEvent, startTime))
 
 	err = cfp.updatePackageToFixedVersion(fixVersionInfo.packageType, impactedPackage, fixVersionInfo.fixVersion, params.RequirementsFile)
 	if err != nil {
 		return err
 	}
+	cfp.emitEvent("manifest_updated", withDuration(baseEvent, startTime))
 
-	clientLog.Info("Checking if there are changes to commit")
+	cfp.getLogger().Info("Checking if there are changes to commit")
 	isClean, err := gitManager.IsClean()
 	if err != nil {
 		return err
@@ -212,21 +314,30 @@ func (cfp *CreateFixPullRequestsCmd) fixSinglePackageAndCreatePR(impactedPackage
 		return fmt.Errorf("there were no changes to commit after fixing the package '%s'", impactedPackage)
 	}
 
-	clientLog.Info("Running git add all and commit")
+	cfp.getLogger().Info("Running git add all and commit")
 	commitString := fmt.Sprintf("[🐸 Frogbot] Upgrade %s to %s", impactedPackage, fixVersionInfo.fixVersion)
 	err = gitManager.AddAllAndCommit(commitString)
 	if err != nil {
 		return err
 	}
-	clientLog.Info("Pushing fix branch:", fixBranchName)
+	cfp.getLogger().Info("Pushing fix branch:", fixBranchName)
 	err = gitManager.Push()
 	if err != nil {
 		return err
 	}
-	clientLog.Info("Creating Pull Request form:", fixBranchName, " to:", params.BaseBranch)
+	cfp.emitEvent("commit_pushed", withDuration(baseEvent, startTime))
+
+	cfp.getLogger().Info("Creating Pull Request form:", fixBranchName, " to:", params.BaseBranch)
 	prBody := commitString + "\n\n" + utils.WhatIsFrogbotMd
+	if cfp.aggregationConflictNote != "" {
+		prBody = commitString + "\n\n**Note:** this fix was not included in an aggregated PR because " + cfp.aggregationConflictNote + "\n\n" + utils.WhatIsFrogbotMd
+	}
 	err = client.CreatePullRequest(context.Background(), params.RepoOwner, params.Repo, fixBranchName, params.BaseBranch, commitString, prBody)
-	return
+	if err != nil {
+		return err
+	}
+	cfp.emitEvent("pr_opened", withDuration(baseEvent, startTime))
+	return nil
 }
 
 func (cfp *CreateFixPullRequestsCmd) updatePackageToFixedVersion(packageType coreutils.Technology, impactedPackage, fixVersion, requirementsFile string) error {
@@ -234,20 +345,22 @@ func (cfp *CreateFixPullRequestsCmd) updatePackageToFixedVersion(packageType cor
 	switch packageType {
 	case coreutils.Go:
 		commandArgs := []string{"get"}
-		err = fixPackageVersionGeneric(commandArgs, coreutils.Go.GetExecCommandName(), impactedPackage, fixVersion, "@v")
+		err = fixPackageVersionGeneric(cfp, commandArgs, coreutils.Go.GetExecCommandName(), impactedPackage, fixVersion, "@v")
 	case coreutils.Npm:
 		commandArgs := []string{"install"}
-		err = fixPackageVersionGeneric(commandArgs, coreutils.Npm.GetExecCommandName(), impactedPackage, fixVersion, "@")
+		err = fixPackageVersionGeneric(cfp, commandArgs, coreutils.Npm.GetExecCommandName(), impactedPackage, fixVersion, "@")
 	case coreutils.Maven:
 		err = fixPackageVersionMaven(cfp, impactedPackage, fixVersion)
 	case coreutils.Yarn:
 		commandArgs := []string{"up"}
-		err = fixPackageVersionGeneric(commandArgs, coreutils.Yarn.GetExecCommandName(), impactedPackage, fixVersion, "@")
+		err = fixPackageVersionGeneric(cfp, commandArgs, coreutils.Yarn.GetExecCommandName(), impactedPackage, fixVersion, "@")
 	case coreutils.Pip:
 		err = fixPackageVersionPip(impactedPackage, fixVersion, requirementsFile)
 	case coreutils.Pipenv:
 		commandArgs := []string{"install"}
-		err = fixPackageVersionGeneric(commandArgs, coreutils.Pipenv.GetExecCommandName(), impactedPackage, fixVersion, "==")
+		err = fixPackageVersionGeneric(cfp, commandArgs, coreutils.Pipenv.GetExecCommandName(), impactedPackage, fixVersion, "==")
+	case rubyBundlerTechnology:
+		err = fixPackageVersionBundler(cfp, impactedPackage, fixVersion, requirementsFile)
 	default:
 		return fmt.Errorf("package type: %s is currently not supported", string(packageType))
 	}
@@ -262,11 +375,11 @@ func (cfp *CreateFixPullRequestsCmd) updatePackageToFixedVersion(packageType cor
 // impactedPackage - Vulnerable package to upgrade
 // fixVersion - The version that fixes the vulnerable package
 // operator - The operator between the impactedPackage to the fixVersion
-func fixPackageVersionGeneric(commandArgs []string, commandName, impactedPackage, fixVersion, operator string) error {
+func fixPackageVersionGeneric(cfp *CreateFixPullRequestsCmd, commandArgs []string, commandName, impactedPackage, fixVersion, operator string) error {
 	fixedPackage := impactedPackage + operator + fixVersion
 	commandArgs = append(commandArgs, fixedPackage)
 	fullCommand := commandName + " " + strings.Join(commandArgs, " ")
-	clientLog.Debug(fmt.Sprintf("Running '%s'", fullCommand))
+	cfp.getLogger().Debug(fmt.Sprintf("Running '%s'", fullCommand))
 	output, err := exec.Command(commandName, commandArgs...).CombinedOutput() // #nosec G204
 	if err != nil {
 		return fmt.Errorf("%s install command failed: %s\n%s", commandName, err.Error(), output)
@@ -280,7 +393,7 @@ func fixPackageVersionMaven(cfp *CreateFixPullRequestsCmd, impactedPackage, fixV
 	// Update the package version. This command updates it only if the version is not a reference to a property.
 	updateVersionArgs := []string{"-B", "versions:use-dep-version", "-Dincludes=" + impactedPackage, "-DdepVersion=" + fixVersion, "-DgenerateBackupPoms=false"}
 	updateVersionCmd := fmt.Sprintf("mvn %s", strings.Join(updateVersionArgs, " "))
-	clientLog.Debug(fmt.Sprintf("Running '%s'", updateVersionCmd))
+	cfp.getLogger().Debug(fmt.Sprintf("Running '%s'", updateVersionCmd))
 	updateVersionOutput, err := exec.Command("mvn", updateVersionArgs...).CombinedOutput() // #nosec G204
 	if err != nil {
 		return fmt.Errorf("mvn command failed: %s\n%s", err.Error(), updateVersionOutput)
@@ -290,7 +403,7 @@ func fixPackageVersionMaven(cfp *CreateFixPullRequestsCmd, impactedPackage, fixV
 	for _, property := range properties {
 		updatePropertyArgs := []string{"-B", "versions:set-property", "-Dproperty=" + property, "-DnewVersion=" + fixVersion, "-DgenerateBackupPoms=false"}
 		updatePropertyCmd := fmt.Sprintf("mvn %s", strings.Join(updatePropertyArgs, " "))
-		clientLog.Debug(fmt.Sprintf("Running '%s'", updatePropertyCmd))
+		cfp.getLogger().Debug(fmt.Sprintf("Running '%s'", updatePropertyCmd))
 		updatePropertyOutput, err := exec.Command("mvn", updatePropertyArgs...).CombinedOutput() // #nosec G204
 		if err != nil {
 			return fmt.Errorf("mvn command failed: %s\n%s", err.Error(), updatePropertyOutput)
@@ -300,6 +413,64 @@ func fixPackageVersionMaven(cfp *CreateFixPullRequestsCmd, impactedPackage, fixV
 	return nil
 }
 
+// extractManifestConstraint returns the raw version requirement declared for impactedPackage in the project's own
+// manifest, for technologies where Frogbot already knows how to read one (Pip's requirements.txt/setup.py and
+// Bundler's Gemfile). It returns "" when tech isn't one of those, the manifest can't be read, or the package isn't
+// pinned there - in every case SelectFixVersion treats an empty constraint as "none known".
+func extractManifestConstraint(tech coreutils.Technology, impactedPackage, requirementsFile string) string {
+	switch tech {
+	case coreutils.Pip, coreutils.Pipenv:
+		return extractPipConstraint(impactedPackage, requirementsFile)
+	case rubyBundlerTechnology:
+		return extractGemConstraint(impactedPackage, requirementsFile)
+	default:
+		return ""
+	}
+}
+
+func extractPipConstraint(impactedPackage, requirementsFile string) string {
+	if requirementsFile == "" {
+		requirementsFile = "setup.py"
+	}
+	data, err := os.ReadFile(filepath.Clean(requirementsFile))
+	if err != nil {
+		return ""
+	}
+	re := regexp.MustCompile(pythonPackageRegexPrefix + regexp.QuoteMeta(impactedPackage) + "(" + pythonPackageRegexSuffix + ")")
+	match := re.FindStringSubmatch(string(data))
+	if len(match) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(match[1])
+}
+
+func extractGemConstraint(impactedPackage, gemfilePath string) string {
+	if gemfilePath == "" {
+		gemfilePath = "Gemfile"
+	}
+	data, err := os.ReadFile(filepath.Clean(gemfilePath))
+	if err != nil {
+		return ""
+	}
+	re := regexp.MustCompile(rubyPackageRegexPrefix + regexp.QuoteMeta(impactedPackage) + rubyPackageRegexSuffix)
+	match := re.FindString(string(data))
+	if match == "" {
+		return ""
+	}
+	// The first quoted token is the gem name itself, the rest are version requirements; turn them into a
+	// comma-separated constraint string matching satisfiesGemConstraint's expected syntax.
+	quoted := regexp.MustCompile(`['"]([^'"]*)['"]`)
+	requirements := quoted.FindAllStringSubmatch(match, -1)
+	if len(requirements) < 2 {
+		return ""
+	}
+	clauses := make([]string, 0, len(requirements)-1)
+	for _, req := range requirements[1:] {
+		clauses = append(clauses, req[1])
+	}
+	return strings.Join(clauses, ",")
+}
+
 func fixPackageVersionPip(impactedPackage, fixVersion, requirementsFile string) error {
 	// This function assumes that the version of the dependencies is statically pinned in the requirements file or inside the 'install_requires' array in the setup.py file
 	fixedPackage := impactedPackage + "==" + fixVersion
@@ -334,6 +505,264 @@ func fixPackageVersionPip(impactedPackage, fixVersion, requirementsFile string)
 	return nil
 }
 
+// fixPackageVersionBundler upgrades impactedPackage to fixVersion using Bundler's conservative resolver, and, if the
+// gem is pinned to an explicit version requirement in the Gemfile, rewrites that requirement so it stays in sync
+// with Gemfile.lock.
+func fixPackageVersionBundler(cfp *CreateFixPullRequestsCmd, impactedPackage, fixVersion, gemfilePath string) error {
+	commandArgs := []string{"update", impactedPackage, "--conservative"}
+	fullCommand := fmt.Sprintf("bundle %s", strings.Join(commandArgs, " "))
+	cfp.getLogger().Debug(fmt.Sprintf("Running '%s'", fullCommand))
+	output, err := exec.Command("bundle", commandArgs...).CombinedOutput() // #nosec G204
+	if err != nil {
+		return fmt.Errorf("bundle update command failed: %s\n%s", err.Error(), output)
+	}
+
+	if gemfilePath == "" {
+		gemfilePath = "Gemfile"
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	fullPath := filepath.Join(wd, gemfilePath)
+	if !strings.HasPrefix(filepath.Clean(fullPath), wd) {
+		return errors.New("wrong Gemfile path input")
+	}
+	data, err := os.ReadFile(filepath.Clean(gemfilePath))
+	if err != nil {
+		return err
+	}
+	currentFile := string(data)
+	// This regex matches the gem declaration along with its version requirement(s), e.g. gem 'rails', '~> 6.1.0'
+	re := regexp.MustCompile(rubyPackageRegexPrefix + regexp.QuoteMeta(impactedPackage) + rubyPackageRegexSuffix)
+	match := re.FindString(currentFile)
+	if match == "" {
+		// The gem isn't pinned in the Gemfile (version is only constrained by Gemfile.lock), nothing to rewrite.
+		return nil
+	}
+	fixedRequirement, err := fixGemRequirement(match, fixVersion)
+	if err != nil {
+		return err
+	}
+	if fixedRequirement == match {
+		return nil
+	}
+	fixedFile := strings.Replace(currentFile, match, fixedRequirement, 1)
+	if err = os.WriteFile(gemfilePath, []byte(fixedFile), 0600); err != nil {
+		return err
+	}
+	// Resolve again against the rewritten requirement, so Gemfile.lock records the same requirement as the Gemfile.
+	output, err = exec.Command("bundle", commandArgs...).CombinedOutput() // #nosec G204
+	if err != nil {
+		return fmt.Errorf("bundle update command failed: %s\n%s", err.Error(), output)
+	}
+	return nil
+}
+
+// fixGemRequirement rewrites the version requirement(s) found in a 'gem' declaration so that fixVersion satisfies
+// them. Pessimistic (~>), comparison (>=, <=, >, <) and exact ('=' or bare) requirements are supported, mirroring
+// the operators RubyGems itself accepts in a Gemfile.
+//
+// RubyGems versions aren't SemVer: patch releases routinely carry a fourth segment (e.g. the Rails security release
+// "6.1.4.1"), which Masterminds/semver rejects outright. Requirement satisfaction is therefore evaluated with
+// compareRubyVersions below instead of the semver package used for Npm/Go/Yarn.
+func fixGemRequirement(gemDeclaration, fixVersion string) (string, error) {
+	quoted := regexp.MustCompile(`['"]([^'"]*)['"]`)
+	requirements := quoted.FindAllStringIndex(gemDeclaration, -1)
+	// The first quoted token is the gem name itself, the rest are version requirements.
+	fixedDeclaration := gemDeclaration
+	for i := len(requirements) - 1; i > 0; i-- {
+		start, end := requirements[i][0], requirements[i][1]
+		requirement := gemDeclaration[start+1 : end-1]
+		satisfies, err := satisfiesGemRequirement(requirement, fixVersion)
+		if err != nil {
+			return "", err
+		}
+		if satisfies {
+			// The existing requirement already allows fixVersion, nothing to change.
+			continue
+		}
+		fixedDeclaration = fixedDeclaration[:start+1] + replaceRequirementVersion(requirement, fixVersion) + fixedDeclaration[end-1:]
+	}
+	return fixedDeclaration, nil
+}
+
+// rubyRequirementRegex matches a single RubyGems version requirement, e.g. "~> 6.1.0", ">= 1.11.0" or "1.2.0".
+var rubyRequirementRegex = regexp.MustCompile(`^\s*(~>|>=|<=|>|<|=)?\s*([\d.]+)\s*$`)
+
+// satisfiesGemRequirement reports whether fixVersion already satisfies a single RubyGems requirement. A bare
+// version with no operator is an implicit "=", matching RubyGems itself.
+func satisfiesGemRequirement(requirement, fixVersion string) (bool, error) {
+	match := rubyRequirementRegex.FindStringSubmatch(requirement)
+	if match == nil {
+		return false, fmt.Errorf("failed parsing gem version requirement %s", requirement)
+	}
+	operator, reqVersion := match[1], match[2]
+	cmp := compareRubyVersions(fixVersion, reqVersion)
+	switch operator {
+	case "", "=":
+		return cmp == 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "~>":
+		// The pessimistic operator allows any version >= reqVersion and below the bound made by dropping
+		// reqVersion's last segment and incrementing the one before it, e.g. "~> 6.1.0" allows [6.1.0, 6.2.0);
+		// a single-segment requirement increments its only segment, e.g. "~> 6" allows [6, 7).
+		return cmp >= 0 && !exceedsPessimisticBound(reqVersion, fixVersion), nil
+	default:
+		return false, fmt.Errorf("unsupported gem version operator: %s", operator)
+	}
+}
+
+// exceedsPessimisticBound reports whether candidate is at or beyond the exclusive upper bound implied by
+// requirement's "~>" operator.
+func exceedsPessimisticBound(requirement, candidate string) bool {
+	bound := strings.Split(requirement, ".")
+	if len(bound) > 1 {
+		bound = bound[:len(bound)-1]
+	}
+	lastIndex := len(bound) - 1
+	n, _ := strconv.Atoi(bound[lastIndex])
+	bound[lastIndex] = strconv.Itoa(n + 1)
+	return compareRubyVersions(candidate, strings.Join(bound, ".")) >= 0
+}
+
+// compareRubyVersions compares two RubyGems version strings segment by segment, treating a missing trailing
+// segment as 0 (so "6.1.4" == "6.1.4.0" and is less than "6.1.4.1").
+func compareRubyVersions(a, b string) int {
+	segmentsA := strings.Split(a, ".")
+	segmentsB := strings.Split(b, ".")
+	for i := 0; i < len(segmentsA) || i < len(segmentsB); i++ {
+		var numA, numB int
+		if i < len(segmentsA) {
+			numA, _ = strconv.Atoi(segmentsA[i])
+		}
+		if i < len(segmentsB) {
+			numB, _ = strconv.Atoi(segmentsB[i])
+		}
+		if numA != numB {
+			return numA - numB
+		}
+	}
+	return 0
+}
+
+// replaceRequirementVersion swaps the version number inside a single RubyGems requirement (e.g. "~> 1.2.0",
+// ">= 1.2.0", "1.2.0") for newVersion, preserving the requirement's operator, except that a strict "<" or ">"
+// becomes "<=" or ">=" so that newVersion satisfies the rewritten requirement.
+func replaceRequirementVersion(requirement, newVersion string) string {
+	operatorRegex := regexp.MustCompile(`^\s*(~>|>=|<=|>|<|=)?\s*`)
+	operatorMatch := operatorRegex.FindStringSubmatch(requirement)
+	operator := operatorMatch[0]
+	switch operatorMatch[1] {
+	case "<":
+		operator = strings.Replace(operator, "<", "<=", 1)
+	case ">":
+		operator = strings.Replace(operator, ">", ">=", 1)
+	}
+	return operator + newVersion
+}
+
+// getLogger returns cfp.logger, lazily initializing it so that package-level helpers and tests that build a
+// CreateFixPullRequestsCmd directly (bypassing Run) still get a usable structured logger.
+func (cfp *CreateFixPullRequestsCmd) getLogger() *logrus.Logger {
+	if cfp.logger == nil {
+		cfp.logger = utils.NewStructuredLogger()
+	}
+	return cfp.logger
+}
+
+// emitEvent logs eventName as a structured log line and forwards it, via cfp.eventEmitter, to whichever external
+// sinks are configured through the environment.
+func (cfp *CreateFixPullRequestsCmd) emitEvent(eventName string, event utils.Event) {
+	event.Event = eventName
+	cfp.getLogger().WithFields(logrus.Fields{
+		"package":         event.Package,
+		"current_version": event.CurrentVersion,
+		"fix_version":     event.FixVersion,
+		"technology":      event.Technology,
+		"branch":          event.Branch,
+		"pr_url":          event.PrUrl,
+		"cve_ids":         event.CveIds,
+		"duration_ms":     event.DurationMs,
+	}).Info(eventName)
+	if cfp.eventEmitter == nil {
+		return
+	}
+	if err := cfp.eventEmitter.Emit(event); err != nil {
+		cfp.getLogger().Debug("Failed emitting event", eventName, "for", event.Package, "-", err.Error())
+	}
+}
+
+// withDuration returns a copy of event with DurationMs set to the elapsed time since startTime.
+func withDuration(event utils.Event, startTime time.Time) utils.Event {
+	event.DurationMs = time.Since(startTime).Milliseconds()
+	return event
+}
+
+// validateFixVersion confirms, against registryClient, that the fix version Frogbot selected for impactedPackage
+// actually exists and hasn't been yanked/retracted/deprecated. If it has, the next higher candidate recorded on
+// fixVersionInfo is tried instead. A nil logger falls back to a freshly constructed structured logger.
+func validateFixVersion(logger *logrus.Logger, registryClient utils.RegistryClient, impactedPackage string, fixVersionInfo FixVersionInfo) (string, error) {
+	if logger == nil {
+		logger = utils.NewStructuredLogger()
+	}
+	candidates := sortedUniqueVersions(fixVersionInfo.candidates, fixVersionInfo.packageType)
+	for _, candidate := range candidates {
+		cmp, err := compareVersions(candidate, fixVersionInfo.fixVersion, fixVersionInfo.packageType)
+		if err != nil {
+			return "", err
+		}
+		if cmp < 0 {
+			// Only the originally selected fix version, or a higher candidate, is an acceptable fallback.
+			continue
+		}
+		exists, err := registryClient.VersionExists(impactedPackage, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed verifying that %s %s exists upstream: %s", impactedPackage, candidate, err.Error())
+		}
+		if !exists {
+			continue
+		}
+		yanked, err := registryClient.IsYanked(impactedPackage, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed checking whether %s %s was yanked upstream: %s", impactedPackage, candidate, err.Error())
+		}
+		if yanked {
+			logger.Info(candidate, "of", impactedPackage, "was yanked upstream, trying the next candidate")
+			continue
+		}
+		return candidate, nil
+	}
+	return "", fmt.Errorf("no valid, non-yanked fix version found upstream for %s among %v", impactedPackage, fixVersionInfo.candidates)
+}
+
+// sortedUniqueVersions dedupes and ascending-sorts versions according to tech's version scheme.
+func sortedUniqueVersions(versions []string, tech coreutils.Technology) []string {
+	seen := make(map[string]bool, len(versions))
+	unique := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		cmp, err := compareVersions(unique[i], unique[j], tech)
+		if err != nil {
+			return unique[i] < unique[j]
+		}
+		return cmp < 0
+	})
+	return unique
+}
+
 func generateFixBranchName(baseBranch, impactedPackage, fixVersion string) (string, error) {
 	uniqueString, err := utils.Md5Hash("frogbot", baseBranch, impactedPackage, fixVersion)
 	if err != nil {
@@ -345,35 +774,48 @@ func generateFixBranchName(baseBranch, impactedPackage, fixVersion string) (stri
 	return fmt.Sprintf("%s-%s-%s", "frogbot", fixedPackageName, uniqueString), nil
 }
 
-///      1.0         --> 1.0 ≤ x
-///      (,1.0]      --> x ≤ 1.0
-///      (,1.0)      --> x &lt; 1.0
-///      [1.0]       --> x == 1.0
-///      (1.0,)      --> 1.0 &lt; x
-///      (1.0, 2.0)   --> 1.0 &lt; x &lt; 2.0
-///      [1.0, 2.0]   --> 1.0 ≤ x ≤ 2.0
-func parseVersionChangeString(fixVersion string) string {
-	latestVersion := strings.Split(fixVersion, ",")[0]
-	if latestVersion[0] == '(' {
-		return ""
-	}
-	latestVersion = strings.Trim(latestVersion, "[")
-	latestVersion = strings.Trim(latestVersion, "]")
-	return latestVersion
-}
+// Affected-version range parsing and fix-version selection now live in fixversionsolver.go, see parseFixedVersionRange
+// and SelectFixVersion.
 
 type FixVersionInfo struct {
 	fixVersion  string
 	packageType coreutils.Technology
+	// candidates holds every fix version seen across all vulnerabilities that impact this package, so that
+	// validateFixVersion has a fallback to reach for if fixVersion turns out to be yanked upstream.
+	candidates []string
+	// currentVersion is the version currently resolved for this package, as reported by Xray, surfaced on emitted
+	// Events so downstream consumers can tell what's being upgraded from, not just what to.
+	currentVersion string
+	// cveIds holds the CVE identifiers (or Xray issue IDs, when no CVE was assigned) of every vulnerability this
+	// fix version addresses, for inclusion in the aggregated-fix PR body.
+	cveIds []string
 }
 
-func NewFixVersionInfo(newFixVersion string, packageType coreutils.Technology) *FixVersionInfo {
-	return &FixVersionInfo{newFixVersion, packageType}
+func NewFixVersionInfo(newFixVersion string, candidates []string, packageType coreutils.Technology, currentVersion string, cveIds []string) *FixVersionInfo {
+	if len(candidates) == 0 {
+		candidates = []string{newFixVersion}
+	}
+	return &FixVersionInfo{newFixVersion, packageType, candidates, currentVersion, cveIds}
 }
 
-func (fvi *FixVersionInfo) UpdateFixVersion(newFixVersion string) {
-	// Update fvi.fixVersion as the maximum version if found a new version that is greater than the previous maximum version.
-	if fvi.fixVersion == "" || version.NewVersion(fvi.fixVersion).Compare(newFixVersion) > 0 {
+func (fvi *FixVersionInfo) UpdateFixVersion(newFixVersion string, candidates []string) error {
+	if len(candidates) == 0 {
+		candidates = []string{newFixVersion}
+	}
+	fvi.candidates = append(fvi.candidates, candidates...)
+	if fvi.fixVersion == "" {
 		fvi.fixVersion = newFixVersion
+		return nil
 	}
+	// Update fvi.fixVersion as the maximum version if found a new version that is greater than the previous maximum
+	// version, using the version scheme native to fvi.packageType rather than the generic dot-separated comparator,
+	// which doesn't know e.g. that Maven's "2.0-rc" sorts before "2.0".
+	cmp, err := compareVersions(fvi.fixVersion, newFixVersion, fvi.packageType)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		fvi.fixVersion = newFixVersion
+	}
+	return nil
 }
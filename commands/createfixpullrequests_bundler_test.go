@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// copyRubyFixture copies the Gemfile/Gemfile.lock fixture under testdata/ruby into dir, returning the Gemfile path.
+func copyRubyFixture(t *testing.T, dir string) string {
+	t.Helper()
+	for _, name := range []string{"Gemfile", "Gemfile.lock"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "ruby", name))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	return filepath.Join(dir, "Gemfile")
+}
+
+func TestExtractGemConstraint(t *testing.T) {
+	gemfilePath := copyRubyFixture(t, t.TempDir())
+
+	assert.Equal(t, "~> 6.1.0", extractGemConstraint("rails", gemfilePath))
+	assert.Equal(t, ">= 1.11.0,< 2.0", extractGemConstraint("nokogiri", gemfilePath))
+	assert.Equal(t, "", extractGemConstraint("no-such-gem", gemfilePath))
+}
+
+func TestFixPackageVersionBundler(t *testing.T) {
+	if _, err := exec.LookPath("bundle"); err != nil {
+		t.Skip("bundle executable not available")
+	}
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	gemfilePath := copyRubyFixture(t, dir)
+	assert.NoError(t, os.Chdir(dir))
+	defer func() { assert.NoError(t, os.Chdir(wd)) }()
+
+	cfp := &CreateFixPullRequestsCmd{}
+	assert.NoError(t, fixPackageVersionBundler(cfp, "rails", "6.1.4.1", gemfilePath))
+
+	data, err := os.ReadFile(gemfilePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "gem 'rails', '~> 6.1.0'")
+}
+
+func TestFixGemRequirement(t *testing.T) {
+	tests := []struct {
+		name           string
+		gemDeclaration string
+		fixVersion     string
+		expected       string
+	}{
+		{
+			name:           "pessimistic operator already satisfied",
+			gemDeclaration: "gem 'rails', '~> 6.1.0'",
+			fixVersion:     "6.1.4",
+			expected:       "gem 'rails', '~> 6.1.0'",
+		},
+		{
+			name:           "pessimistic operator allows a four-segment patch release within its window",
+			gemDeclaration: "gem 'rails', '~> 6.1.0'",
+			fixVersion:     "6.1.4.1",
+			expected:       "gem 'rails', '~> 6.1.0'",
+		},
+		{
+			name:           "pessimistic operator needs rewrite for a release outside its window",
+			gemDeclaration: "gem 'rails', '~> 6.1.0'",
+			fixVersion:     "6.2.1.1",
+			expected:       "gem 'rails', '~> 6.2.1.1'",
+		},
+		{
+			name:           "multiple requirements, all already satisfied",
+			gemDeclaration: "gem \"nokogiri\", \">= 1.11.0\", \"< 2.0\"",
+			fixVersion:     "1.13.0",
+			expected:       "gem \"nokogiri\", \">= 1.11.0\", \"< 2.0\"",
+		},
+		{
+			name:           "exact version pin",
+			gemDeclaration: "gem 'loofah', '1.3.0'",
+			fixVersion:     "1.3.1",
+			expected:       "gem 'loofah', '1.3.1'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixed, err := fixGemRequirement(test.gemDeclaration, test.fixVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, fixed)
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jfrog/gofrog/version"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+)
+
+// VersionRange describes, in OSV terms, a single affected-version window reported by Xray for a vulnerability.
+// Introduced and LastAffected bound the range of versions that are vulnerable; Fixed is the version at which the
+// range closes, i.e. the first version that is no longer affected. Any of the three may be empty when the
+// underlying advisory leaves that bound open.
+//
+// Xray reports these windows using Maven-style interval notation:
+//
+//	1.0         --> 1.0 ≤ x             (Fixed: "1.0")
+//	(,1.0]      --> x ≤ 1.0             (LastAffected: "1.0")
+//	(,1.0)      --> x < 1.0             (Fixed: "1.0")
+//	[1.0]       --> x == 1.0            (Introduced: "1.0", LastAffected: "1.0")
+//	(1.0,)      --> 1.0 < x             (Introduced: "1.0")
+//	(1.0, 2.0)  --> 1.0 < x < 2.0       (Introduced: "1.0", Fixed: "2.0")
+//	[1.0, 2.0]  --> 1.0 ≤ x ≤ 2.0       (Introduced: "1.0", LastAffected: "2.0")
+type VersionRange struct {
+	Introduced   string
+	Fixed        string
+	LastAffected string
+}
+
+// parseFixedVersionRanges parses every entry of a vulnerability's FixedVersions into a VersionRange.
+func parseFixedVersionRanges(fixedVersions []string) ([]VersionRange, error) {
+	ranges := make([]VersionRange, 0, len(fixedVersions))
+	for _, raw := range fixedVersions {
+		rng, err := parseFixedVersionRange(raw)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, rng)
+	}
+	return ranges, nil
+}
+
+// parseFixedVersionRange parses a single Maven-style interval-notation entry into a VersionRange.
+func parseFixedVersionRange(raw string) (VersionRange, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return VersionRange{}, fmt.Errorf("empty version range")
+	}
+	// A bare version, e.g. "1.0", means "the package is fixed starting at this version".
+	if trimmed[0] != '(' && trimmed[0] != '[' {
+		return VersionRange{Fixed: trimmed}, nil
+	}
+	if len(trimmed) < 2 {
+		return VersionRange{}, fmt.Errorf("malformed version range: %s", raw)
+	}
+	inclusiveLower := trimmed[0] == '['
+	inclusiveUpper := trimmed[len(trimmed)-1] == ']'
+	inner := trimmed[1 : len(trimmed)-1]
+
+	parts := strings.SplitN(inner, ",", 2)
+	lower := strings.TrimSpace(parts[0])
+	if len(parts) == 1 {
+		// "[1.0]" - an exact pin, both bounds are the same version.
+		return VersionRange{Introduced: lower, LastAffected: lower}, nil
+	}
+	upper := strings.TrimSpace(parts[1])
+
+	rng := VersionRange{}
+	if lower != "" {
+		rng.Introduced = lower
+	}
+	if upper != "" {
+		if inclusiveUpper {
+			rng.LastAffected = upper
+		} else {
+			rng.Fixed = upper
+		}
+	}
+	_ = inclusiveLower // the lower bound of an affected range is never itself a fix candidate
+	return rng, nil
+}
+
+// SelectFixVersion picks the fix version for an impacted package out of the affected-version ranges Xray reported
+// for it. It compares candidates using the version scheme native to tech (SemVer for Npm/Go/Yarn, and a native
+// PEP 440 or Maven comparator for Pip/Pipenv/Maven; see compareVersions), and returns the smallest fix version
+// that is both strictly greater than the version currently resolved in the lock file and, when constraint is
+// non-empty, satisfies the project's own manifest-declared version requirement for the package (e.g. a Gemfile's
+// "~> 6.1.0" or a requirements.txt's ">=1.0,<2.0"). Pass an empty constraint when none is known.
+func SelectFixVersion(ranges []VersionRange, current, constraint string, tech coreutils.Technology) (string, error) {
+	candidates, err := candidateFixVersions(ranges, current, constraint, tech)
+	if err != nil {
+		return "", err
+	}
+	var best string
+	for _, candidate := range candidates {
+		if best == "" {
+			best = candidate
+			continue
+		}
+		cmp, err := compareVersions(candidate, best, tech)
+		if err != nil {
+			return "", err
+		}
+		if cmp < 0 {
+			best = candidate
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no fix version found that is newer than the currently installed version %q and satisfies the declared requirement %q", current, constraint)
+	}
+	return best, nil
+}
+
+// candidateFixVersions returns every fix boundary across ranges that is both strictly newer than current and, when
+// constraint is non-empty, satisfies it - the full actionable candidate set, not just the one SelectFixVersion would
+// pick. FixVersionInfo keeps this whole set so validateFixVersion has real fallbacks to try if its chosen version
+// turns out to be yanked upstream, instead of only ever knowing about the single version that was selected.
+func candidateFixVersions(ranges []VersionRange, current, constraint string, tech coreutils.Technology) ([]string, error) {
+	var candidates []string
+	for _, rng := range ranges {
+		candidate := rng.Fixed
+		if candidate == "" {
+			// No explicit fix boundary in this range - nothing actionable to upgrade to.
+			continue
+		}
+		if current != "" {
+			cmp, err := compareVersions(candidate, current, tech)
+			if err != nil {
+				return nil, err
+			}
+			if cmp <= 0 {
+				continue
+			}
+		}
+		if constraint != "" {
+			satisfies, err := satisfiesConstraint(candidate, constraint, tech)
+			if err != nil {
+				return nil, err
+			}
+			if !satisfies {
+				continue
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// satisfiesConstraint reports whether versionStr satisfies constraint, a manifest-declared version requirement for
+// a package, using the constraint syntax native to tech. An empty constraint is always satisfied.
+func satisfiesConstraint(versionStr, constraint string, tech coreutils.Technology) (bool, error) {
+	if constraint == "" {
+		return true, nil
+	}
+	switch tech {
+	case coreutils.Npm, coreutils.Go, coreutils.Yarn:
+		parsedConstraint, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return false, fmt.Errorf("failed parsing constraint %q: %s", constraint, err.Error())
+		}
+		parsedVersion, err := semver.NewVersion(versionStr)
+		if err != nil {
+			return false, fmt.Errorf("failed parsing %q as SemVer: %s", versionStr, err.Error())
+		}
+		return parsedConstraint.Check(parsedVersion), nil
+	case coreutils.Pip, coreutils.Pipenv:
+		return satisfiesPep440Constraint(versionStr, constraint)
+	case coreutils.Maven:
+		return satisfiesMavenConstraint(versionStr, constraint)
+	case rubyBundlerTechnology:
+		return satisfiesGemConstraint(versionStr, constraint)
+	default:
+		return true, nil
+	}
+}
+
+// compareVersions compares two version strings using the algebra native to tech. It returns a negative number if a
+// precedes b, zero if they're equal, and a positive number if a follows b.
+func compareVersions(a, b string, tech coreutils.Technology) (int, error) {
+	switch tech {
+	case coreutils.Npm, coreutils.Go, coreutils.Yarn:
+		semverA, err := semver.NewVersion(a)
+		if err != nil {
+			return 0, fmt.Errorf("failed parsing %q as SemVer: %s", a, err.Error())
+		}
+		semverB, err := semver.NewVersion(b)
+		if err != nil {
+			return 0, fmt.Errorf("failed parsing %q as SemVer: %s", b, err.Error())
+		}
+		return semverA.Compare(semverB), nil
+	case coreutils.Pip, coreutils.Pipenv:
+		return comparePep440Versions(a, b)
+	case coreutils.Maven:
+		return compareMavenVersions(a, b), nil
+	default:
+		// Ruby/Bundler (no coreutils constant; routinely carries a fourth version segment, e.g. "6.1.4.1") and any
+		// other technology fall back to the generic dot-separated comparator already used elsewhere in Frogbot.
+		// gofrog's Compare reports how its argument ranks against the receiver, so b is the receiver here.
+		return version.NewVersion(b).Compare(a), nil
+	}
+}
+
+// mavenSegmentSplit splits a Maven version string into its dot/hyphen-delimited segments, e.g. "2.0-beta-1" ->
+// ["2", "0", "beta", "1"].
+var mavenSegmentSplit = regexp.MustCompile(`[.\-]`)
+
+// mavenQualifierRank ranks Maven's well-known textual version qualifiers by release-cadence precedence, mirroring
+// Maven's ComparableVersion (e.g. "2.0-beta" < "2.0-rc" < "2.0" < "2.0-sp"). A missing qualifier (a release with no
+// suffix) is equivalent to "release".
+var mavenQualifierRank = map[string]int{
+	"alpha": 0, "a": 0,
+	"beta": 1, "b": 1,
+	"milestone": 2, "m": 2,
+	"rc": 3, "cr": 3,
+	"snapshot": 4,
+	"":         5, "ga": 5, "final": 5, "release": 5,
+	"sp": 6,
+}
+
+// compareMavenVersions compares two Maven version strings the way Maven's ComparableVersion ranks them: numeric
+// segments compare numerically, and well-known textual qualifiers rank in release-cadence order rather than
+// alphabetically. A qualifier Maven doesn't define ranks just above "sp" and is compared alphabetically against
+// other unrecognized qualifiers.
+func compareMavenVersions(a, b string) int {
+	segmentsA := mavenSegmentSplit.Split(a, -1)
+	segmentsB := mavenSegmentSplit.Split(b, -1)
+	for i := 0; i < len(segmentsA) || i < len(segmentsB); i++ {
+		var segA, segB string
+		if i < len(segmentsA) {
+			segA = segmentsA[i]
+		}
+		if i < len(segmentsB) {
+			segB = segmentsB[i]
+		}
+		if cmp := compareMavenSegment(segA, segB); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func compareMavenSegment(a, b string) int {
+	rankA, alphaA := mavenSegmentRank(a)
+	rankB, alphaB := mavenSegmentRank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+	return strings.Compare(alphaA, alphaB)
+}
+
+// mavenSegmentRank maps a single Maven version segment to a release-cadence rank: numeric segments rank above
+// every qualifier except the ones at or above the "release" tier, and a nonzero numeric segment always outranks a
+// qualifier. The alpha return value is only meaningful (non-empty) for unrecognized qualifiers, to break ties
+// alphabetically.
+func mavenSegmentRank(segment string) (rank int, alpha string) {
+	if num, err := strconv.Atoi(segment); err == nil {
+		if num == 0 {
+			return mavenQualifierRank[""], ""
+		}
+		return mavenQualifierRank["sp"] + 1 + num, ""
+	}
+	lower := strings.ToLower(segment)
+	if rank, known := mavenQualifierRank[lower]; known {
+		return rank, ""
+	}
+	return mavenQualifierRank["sp"] + 1, lower
+}
+
+// satisfiesMavenConstraint reports whether versionStr falls inside constraint, a Maven dependency version-range
+// expression such as "[1.0,2.0)", "(,1.5]" or a bare exact pin like "1.2.3".
+func satisfiesMavenConstraint(versionStr, constraint string) (bool, error) {
+	trimmed := strings.TrimSpace(constraint)
+	if trimmed == "" {
+		return true, nil
+	}
+	if trimmed[0] != '[' && trimmed[0] != '(' {
+		return compareMavenVersions(versionStr, trimmed) == 0, nil
+	}
+	if len(trimmed) < 2 {
+		return false, fmt.Errorf("malformed maven version range constraint: %s", constraint)
+	}
+	inclusiveLower := trimmed[0] == '['
+	inclusiveUpper := trimmed[len(trimmed)-1] == ']'
+	inner := trimmed[1 : len(trimmed)-1]
+	parts := strings.SplitN(inner, ",", 2)
+	lower := strings.TrimSpace(parts[0])
+	if len(parts) == 1 {
+		// "[1.0]" - an exact pin, both bounds are the same version.
+		return compareMavenVersions(versionStr, lower) == 0, nil
+	}
+	upper := strings.TrimSpace(parts[1])
+	if lower != "" {
+		cmp := compareMavenVersions(versionStr, lower)
+		if (inclusiveLower && cmp < 0) || (!inclusiveLower && cmp <= 0) {
+			return false, nil
+		}
+	}
+	if upper != "" {
+		cmp := compareMavenVersions(versionStr, upper)
+		if (inclusiveUpper && cmp > 0) || (!inclusiveUpper && cmp >= 0) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// satisfiesGemConstraint reports whether versionStr satisfies every comma-separated RubyGems requirement in
+// constraint, e.g. "~> 1.4.2, < 2.0".
+func satisfiesGemConstraint(versionStr, constraint string) (bool, error) {
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		satisfies, err := satisfiesGemRequirement(clause, versionStr)
+		if err != nil {
+			return false, err
+		}
+		if !satisfies {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// pep440NoSegment marks a PEP 440 post- or dev-release segment as absent from the version string.
+const pep440NoSegment = -1
+
+// pep440Regex parses a PEP 440 version identifier (https://peps.python.org/pep-0440/) into its epoch, release,
+// pre-release, post-release and development-release components.
+var pep440Regex = regexp.MustCompile(`(?i)^(?:(\d+)!)?(\d+(?:\.\d+)*)(?:(a|b|c|rc|alpha|beta|pre|preview)(\d*))?(?:[-_.]?(post|rev|r)(\d*))?(?:[-_.]?(dev)(\d*))?$`)
+
+// pep440PreReleaseRank ranks a PEP 440 pre-release label by precedence: alpha < beta < rc/c/pre/preview.
+var pep440PreReleaseRank = map[string]int{
+	"a": 0, "alpha": 0,
+	"b": 1, "beta": 1,
+	"c": 2, "rc": 2, "pre": 2, "preview": 2,
+}
+
+type pep440Version struct {
+	epoch    int
+	release  []int
+	preLabel string
+	preNum   int
+	post     int
+	dev      int
+}
+
+// parsePep440 parses a single PEP 440 version identifier.
+func parsePep440(v string) (pep440Version, error) {
+	match := pep440Regex.FindStringSubmatch(strings.TrimSpace(v))
+	if match == nil {
+		return pep440Version{}, fmt.Errorf("failed parsing %q as a PEP 440 version", v)
+	}
+	parsed := pep440Version{post: pep440NoSegment, dev: pep440NoSegment}
+	if match[1] != "" {
+		parsed.epoch, _ = strconv.Atoi(match[1])
+	}
+	for _, segment := range strings.Split(match[2], ".") {
+		n, _ := strconv.Atoi(segment)
+		parsed.release = append(parsed.release, n)
+	}
+	if match[3] != "" {
+		parsed.preLabel = strings.ToLower(match[3])
+		parsed.preNum, _ = strconv.Atoi(zeroIfEmpty(match[4]))
+	}
+	if match[5] != "" {
+		parsed.post, _ = strconv.Atoi(zeroIfEmpty(match[6]))
+	}
+	if match[7] != "" {
+		parsed.dev, _ = strconv.Atoi(zeroIfEmpty(match[8]))
+	}
+	return parsed, nil
+}
+
+func zeroIfEmpty(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// comparePep440Versions compares two PEP 440 version identifiers following the ordering rules of the spec: a dev
+// release sorts before the release it precedes, a pre-release sorts before it, and a post-release sorts after it
+// (e.g. "1.0.dev1" < "1.0a1" < "1.0" < "1.0.post1").
+func comparePep440Versions(a, b string) (int, error) {
+	parsedA, err := parsePep440(a)
+	if err != nil {
+		return 0, err
+	}
+	parsedB, err := parsePep440(b)
+	if err != nil {
+		return 0, err
+	}
+	if parsedA.epoch != parsedB.epoch {
+		return parsedA.epoch - parsedB.epoch, nil
+	}
+	if cmp := compareIntSlices(parsedA.release, parsedB.release); cmp != 0 {
+		return cmp, nil
+	}
+	if cmp := comparePep440PreRelease(parsedA, parsedB); cmp != 0 {
+		return cmp, nil
+	}
+	if parsedA.post != parsedB.post {
+		return parsedA.post - parsedB.post, nil
+	}
+	if parsedA.dev != parsedB.dev {
+		if parsedA.dev == pep440NoSegment {
+			return 1, nil
+		}
+		if parsedB.dev == pep440NoSegment {
+			return -1, nil
+		}
+		return parsedA.dev - parsedB.dev, nil
+	}
+	return 0, nil
+}
+
+func comparePep440PreRelease(a, b pep440Version) int {
+	rankA, hasA := pep440PreReleaseRank[a.preLabel], a.preLabel != ""
+	rankB, hasB := pep440PreReleaseRank[b.preLabel], b.preLabel != ""
+	if !hasA && !hasB {
+		return 0
+	}
+	if !hasA {
+		// a is a final release, which always sorts after any pre-release of the same release segment.
+		return 1
+	}
+	if !hasB {
+		return -1
+	}
+	if rankA != rankB {
+		return rankA - rankB
+	}
+	return a.preNum - b.preNum
+}
+
+func compareIntSlices(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// pep440SpecifierRegex matches a single clause of a PEP 440 version specifier, e.g. ">=1.0" in ">=1.0,<2.0".
+var pep440SpecifierRegex = regexp.MustCompile(`^(==|!=|<=|>=|<|>|~=)\s*(\S+)$`)
+
+// satisfiesPep440Constraint reports whether versionStr satisfies every comma-separated clause of a PEP 440 version
+// specifier, e.g. ">=1.0,<2.0" or "~=1.4.2".
+func satisfiesPep440Constraint(versionStr, constraint string) (bool, error) {
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		match := pep440SpecifierRegex.FindStringSubmatch(clause)
+		if match == nil {
+			return false, fmt.Errorf("failed parsing PEP 440 specifier %q", clause)
+		}
+		operator, boundVersion := match[1], match[2]
+		cmp, err := comparePep440Versions(versionStr, boundVersion)
+		if err != nil {
+			return false, err
+		}
+		var satisfies bool
+		switch operator {
+		case "==":
+			satisfies = cmp == 0
+		case "!=":
+			satisfies = cmp != 0
+		case "<=":
+			satisfies = cmp <= 0
+		case ">=":
+			satisfies = cmp >= 0
+		case "<":
+			satisfies = cmp < 0
+		case ">":
+			satisfies = cmp > 0
+		case "~=":
+			satisfies = cmp >= 0 && pep440CompatibleRelease(versionStr, boundVersion)
+		}
+		if !satisfies {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// pep440CompatibleRelease implements the "~=" compatible-release clause: "~=1.4.2" means ">=1.4.2, ==1.4.*" - every
+// release segment but the last must match boundVersion's exactly.
+func pep440CompatibleRelease(versionStr, boundVersion string) bool {
+	parsedBound, err := parsePep440(boundVersion)
+	if err != nil || len(parsedBound.release) < 2 {
+		return false
+	}
+	parsedVersion, err := parsePep440(versionStr)
+	if err != nil {
+		return false
+	}
+	prefixLen := len(parsedBound.release) - 1
+	if len(parsedVersion.release) < prefixLen {
+		return false
+	}
+	return compareIntSlices(parsedVersion.release[:prefixLen], parsedBound.release[:prefixLen]) == 0
+}
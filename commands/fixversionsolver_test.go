@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFixedVersionRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected VersionRange
+	}{
+		{"bare version", "1.0", VersionRange{Fixed: "1.0"}},
+		{"inclusive upper only", "(,1.0]", VersionRange{LastAffected: "1.0"}},
+		{"exclusive upper only", "(,1.0)", VersionRange{Fixed: "1.0"}},
+		{"exact pin", "[1.0]", VersionRange{Introduced: "1.0", LastAffected: "1.0"}},
+		{"exclusive lower, unbounded upper", "(1.0,)", VersionRange{Introduced: "1.0"}},
+		{"exclusive both bounds", "(1.0, 2.0)", VersionRange{Introduced: "1.0", Fixed: "2.0"}},
+		{"inclusive both bounds", "[1.0, 2.0]", VersionRange{Introduced: "1.0", LastAffected: "2.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := parseFixedVersionRange(test.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	_, err := parseFixedVersionRange("")
+	assert.Error(t, err)
+}
+
+func TestSelectFixVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		ranges     []VersionRange
+		current    string
+		constraint string
+		tech       coreutils.Technology
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name:     "npm picks smallest fix above current",
+			ranges:   []VersionRange{{Fixed: "2.0.0"}, {Fixed: "3.0.0"}},
+			current:  "1.5.0",
+			tech:     coreutils.Npm,
+			expected: "2.0.0",
+		},
+		{
+			name:     "go module semver",
+			ranges:   []VersionRange{{Fixed: "1.2.4"}},
+			current:  "1.2.3",
+			tech:     coreutils.Go,
+			expected: "1.2.4",
+		},
+		{
+			name:     "candidate not newer than current is skipped",
+			ranges:   []VersionRange{{Fixed: "1.0.0"}, {Fixed: "1.2.0"}},
+			current:  "1.1.0",
+			tech:     coreutils.Yarn,
+			expected: "1.2.0",
+		},
+		{
+			name:     "maven compares numeric segments numerically, not the generic comparator",
+			ranges:   []VersionRange{{Fixed: "2.9.10.4"}},
+			current:  "2.9.8",
+			tech:     coreutils.Maven,
+			expected: "2.9.10.4",
+		},
+		{
+			name:     "pip compares versions using PEP 440 rules, not the generic comparator",
+			ranges:   []VersionRange{{Fixed: "1.7.1"}},
+			current:  "1.6.4",
+			tech:     coreutils.Pip,
+			expected: "1.7.1",
+		},
+		{
+			name:       "candidate violating the manifest-declared constraint is skipped",
+			ranges:     []VersionRange{{Fixed: "2.0.0"}, {Fixed: "1.9.0"}},
+			current:    "1.5.0",
+			constraint: ">=1.0.0, <2.0.0",
+			tech:       coreutils.Npm,
+			expected:   "1.9.0",
+		},
+		{
+			name:    "no fixed boundary in any range",
+			ranges:  []VersionRange{{Introduced: "1.0.0"}},
+			current: "1.5.0",
+			tech:    coreutils.Npm,
+			wantErr: true,
+		},
+		{
+			name:       "no candidate satisfies the manifest-declared constraint",
+			ranges:     []VersionRange{{Fixed: "2.0.0"}},
+			current:    "1.5.0",
+			constraint: "<2.0.0",
+			tech:       coreutils.Npm,
+			wantErr:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := SelectFixVersion(test.ranges, test.current, test.constraint, test.tech)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestCompareMavenVersions(t *testing.T) {
+	assert.True(t, compareMavenVersions("2.9.10.4", "2.9.8") > 0)
+	assert.True(t, compareMavenVersions("2.0-beta", "2.0-rc") < 0)
+	assert.True(t, compareMavenVersions("2.0-rc", "2.0") < 0)
+	assert.True(t, compareMavenVersions("2.0", "2.0-sp") < 0)
+	assert.Equal(t, 0, compareMavenVersions("1.0.0", "1.0.0"))
+}
+
+func TestComparePep440Versions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.7.1", "1.6.4", 1},
+		{"1.0.dev1", "1.0a1", -1},
+		{"1.0a1", "1.0", -1},
+		{"1.0", "1.0.post1", -1},
+		{"1.0.post1", "1.0.post2", -1},
+		{"1!1.0", "2.0", 1},
+	}
+	for _, test := range tests {
+		cmp, err := comparePep440Versions(test.a, test.b)
+		assert.NoError(t, err)
+		if test.expected > 0 {
+			assert.Truef(t, cmp > 0, "expected %s > %s", test.a, test.b)
+		} else if test.expected < 0 {
+			assert.Truef(t, cmp < 0, "expected %s < %s", test.a, test.b)
+		} else {
+			assert.Zerof(t, cmp, "expected %s == %s", test.a, test.b)
+		}
+	}
+}
+
+func TestSatisfiesConstraint(t *testing.T) {
+	tests := []struct {
+		name       string
+		versionStr string
+		constraint string
+		tech       coreutils.Technology
+		expected   bool
+	}{
+		{"npm range satisfied", "1.5.0", ">=1.0.0, <2.0.0", coreutils.Npm, true},
+		{"npm range violated", "2.5.0", ">=1.0.0, <2.0.0", coreutils.Npm, false},
+		{"pep440 range satisfied", "1.5.0", ">=1.0,<2.0", coreutils.Pip, true},
+		{"pep440 range violated", "2.5.0", ">=1.0,<2.0", coreutils.Pip, false},
+		{"pep440 compatible release satisfied", "1.4.5", "~=1.4.2", coreutils.Pip, true},
+		{"pep440 compatible release violated", "1.5.0", "~=1.4.2", coreutils.Pip, false},
+		{"maven range satisfied", "1.5.0", "[1.0.0,2.0.0)", coreutils.Maven, true},
+		{"maven range violated", "2.0.0", "[1.0.0,2.0.0)", coreutils.Maven, false},
+		{"gem pessimistic satisfied", "6.1.4.1", "~> 6.1.0", rubyBundlerTechnology, true},
+		{"gem pessimistic violated", "6.2.0", "~> 6.1.0", rubyBundlerTechnology, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := satisfiesConstraint(test.versionStr, test.constraint, test.tech)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
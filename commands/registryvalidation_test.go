@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRegistryClient is a test double for utils.RegistryClient that lets tests declare which versions exist and
+// which are yanked, without making any network calls.
+type mockRegistryClient struct {
+	existingVersions map[string]bool
+	yankedVersions   map[string]bool
+}
+
+func (m *mockRegistryClient) VersionExists(_, pkgVersion string) (bool, error) {
+	return m.existingVersions[pkgVersion], nil
+}
+
+func (m *mockRegistryClient) ListPublishedVersions(_ string) ([]string, error) {
+	versions := make([]string, 0, len(m.existingVersions))
+	for v := range m.existingVersions {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (m *mockRegistryClient) IsYanked(_, pkgVersion string) (bool, error) {
+	return m.yankedVersions[pkgVersion], nil
+}
+
+func TestValidateFixVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		fixVersionInfo  FixVersionInfo
+		registryClient  *mockRegistryClient
+		expectedVersion string
+		wantErr         bool
+	}{
+		{
+			name:           "selected version exists and is not yanked",
+			fixVersionInfo: FixVersionInfo{fixVersion: "1.2.3", packageType: coreutils.Npm, candidates: []string{"1.2.3"}},
+			registryClient: &mockRegistryClient{existingVersions: map[string]bool{"1.2.3": true}},
+			expectedVersion: "1.2.3",
+		},
+		{
+			name:           "selected version was yanked, falls back to next candidate",
+			fixVersionInfo: FixVersionInfo{fixVersion: "1.2.3", packageType: coreutils.Npm, candidates: []string{"1.2.3", "1.3.0"}},
+			registryClient: &mockRegistryClient{
+				existingVersions: map[string]bool{"1.2.3": true, "1.3.0": true},
+				yankedVersions:   map[string]bool{"1.2.3": true},
+			},
+			expectedVersion: "1.3.0",
+		},
+		{
+			name:           "no candidate is resolvable upstream",
+			fixVersionInfo: FixVersionInfo{fixVersion: "1.2.3", packageType: coreutils.Npm, candidates: []string{"1.2.3"}},
+			registryClient: &mockRegistryClient{existingVersions: map[string]bool{}},
+			wantErr:        true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := validateFixVersion(nil, test.registryClient, "some-package", test.fixVersionInfo)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedVersion, actual)
+		})
+	}
+}
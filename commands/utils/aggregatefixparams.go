@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// AggregateFixesEnvVar enables aggregated-fix mode: resolving every impacted package's fix version at once and
+// opening a single combined PR instead of one PR per package, whenever it's possible to do so without any package
+// requiring conflicting transitive dependency versions.
+//
+// This isn't a FrogbotParams field because FrogbotParams isn't defined anywhere in this checkout; gating the
+// feature through the environment keeps it consistent with LogFormatEnvVar and the other toggles in eventemitter.go.
+const AggregateFixesEnvVar = "FROGBOT_AGGREGATE_FIXES"
+
+// AggregateFixesEnabled reports whether aggregated-fix mode is turned on via AggregateFixesEnvVar.
+func AggregateFixesEnabled() bool {
+	return strings.EqualFold(os.Getenv(AggregateFixesEnvVar), "true")
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogFormatEnvVar selects the format NewStructuredLogger's output is rendered in: "text" (the default) or "json" for
+// machine-readable, structured logging.
+const LogFormatEnvVar = "FROGBOT_LOG_FORMAT"
+
+// EventWebhookEnvVar, when set, is a URL that every fix/scan lifecycle Event is POSTed to as JSON.
+const EventWebhookEnvVar = "FROGBOT_EVENT_WEBHOOK_URL"
+
+// EventFilePathEnvVar, when set, is a file path that every fix/scan lifecycle Event is appended to as JSON lines.
+const EventFilePathEnvVar = "FROGBOT_EVENT_FILE_PATH"
+
+const eventSinkRequestTimeout = 10 * time.Second
+
+// Event is a single fix/scan lifecycle occurrence, emitted with stable keys so that downstream SIEM/audit systems
+// can reconstruct exactly which CVEs were auto-remediated on which commit.
+type Event struct {
+	Event          string   `json:"event"`
+	Package        string   `json:"package,omitempty"`
+	CurrentVersion string   `json:"current_version,omitempty"`
+	FixVersion     string   `json:"fix_version,omitempty"`
+	Technology     string   `json:"technology,omitempty"`
+	Branch         string   `json:"branch,omitempty"`
+	PrUrl          string   `json:"pr_url,omitempty"`
+	CveIds         []string `json:"cve_ids,omitempty"`
+	DurationMs     int64    `json:"duration_ms,omitempty"`
+}
+
+// NewStructuredLogger returns a logrus.Logger whose output format is controlled by LogFormatEnvVar.
+func NewStructuredLogger() *logrus.Logger {
+	logger := logrus.New()
+	if strings.EqualFold(os.Getenv(LogFormatEnvVar), "json") {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{})
+	}
+	return logger
+}
+
+// EventEmitter publishes Events to every sink configured through the environment: an optional webhook URL and/or
+// an optional file path. Either, both or neither may be set; Emit is a no-op when neither is configured.
+type EventEmitter struct {
+	webhookUrl string
+	filePath   string
+	httpClient *http.Client
+	fileMutex  sync.Mutex
+}
+
+// NewEventEmitterFromEnv builds an EventEmitter from EventWebhookEnvVar and EventFilePathEnvVar.
+func NewEventEmitterFromEnv() *EventEmitter {
+	return &EventEmitter{
+		webhookUrl: os.Getenv(EventWebhookEnvVar),
+		filePath:   os.Getenv(EventFilePathEnvVar),
+		httpClient: &http.Client{Timeout: eventSinkRequestTimeout},
+	}
+}
+
+// Emit publishes event to every configured sink. It returns an aggregated error if any sink failed, but still
+// attempts every other sink first.
+func (e *EventEmitter) Emit(event Event) error {
+	if e.webhookUrl == "" && e.filePath == "" {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	if e.webhookUrl != "" {
+		if err = e.postToWebhook(payload); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if e.filePath != "" {
+		if err = e.appendToFile(payload); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed emitting event %q to %d sink(s): %s", event.Event, len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (e *EventEmitter) postToWebhook(payload []byte) error {
+	resp, err := e.httpClient.Post(e.webhookUrl, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("event webhook %s returned status %d", e.webhookUrl, resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *EventEmitter) appendToFile(payload []byte) error {
+	e.fileMutex.Lock()
+	defer e.fileMutex.Unlock()
+	file, err := os.OpenFile(e.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(payload, '\n'))
+	return err
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventEmitter_AppendsToFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "events.jsonl")
+	emitter := &EventEmitter{filePath: filePath}
+
+	assert.NoError(t, emitter.Emit(Event{Event: "branch_created", Package: "lodash", FixVersion: "4.17.21"}))
+	assert.NoError(t, emitter.Emit(Event{Event: "pr_opened", Package: "lodash", FixVersion: "4.17.21"}))
+
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"event":"branch_created"`)
+	assert.Contains(t, string(data), `"event":"pr_opened"`)
+}
+
+func TestEventEmitter_NoopWithoutSinks(t *testing.T) {
+	emitter := &EventEmitter{}
+	assert.NoError(t, emitter.Emit(Event{Event: "branch_created"}))
+}
@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+)
+
+// registryRequestTimeout bounds every outgoing call to an upstream package registry, so a slow or unreachable
+// registry can't hang the fix-PR pipeline indefinitely.
+const registryRequestTimeout = 10 * time.Second
+
+// RegistryClient confirms that a fix version Frogbot picked for an impacted package actually exists, and is safe
+// to use, on the package's upstream registry. Each supported Technology has its own implementation, since every
+// ecosystem exposes this information through a different API.
+type RegistryClient interface {
+	// VersionExists reports whether pkgVersion is a real, resolvable release of packageName.
+	VersionExists(packageName, pkgVersion string) (bool, error)
+	// ListPublishedVersions returns every version of packageName the registry has published.
+	ListPublishedVersions(packageName string) ([]string, error)
+	// IsYanked reports whether pkgVersion has been yanked, deprecated or retracted upstream, and should therefore
+	// not be used as a fix version even though it technically exists.
+	IsYanked(packageName, pkgVersion string) (bool, error)
+}
+
+// NewRegistryClient returns the RegistryClient implementation for tech.
+func NewRegistryClient(tech coreutils.Technology) (RegistryClient, error) {
+	httpClient := &http.Client{Timeout: registryRequestTimeout}
+	switch tech {
+	case coreutils.Go:
+		return &goProxyClient{proxyUrl: "https://proxy.golang.org", httpClient: httpClient}, nil
+	case coreutils.Npm, coreutils.Yarn:
+		return &npmRegistryClient{registryUrl: "https://registry.npmjs.org", httpClient: httpClient}, nil
+	case coreutils.Pip, coreutils.Pipenv:
+		return &pypiRegistryClient{registryUrl: "https://pypi.org", httpClient: httpClient}, nil
+	case coreutils.Maven:
+		return &mavenCentralClient{baseUrl: "https://repo1.maven.org/maven2", httpClient: httpClient}, nil
+	default:
+		return nil, fmt.Errorf("no registry client available for technology: %s", string(tech))
+	}
+}
+
+type goProxyClient struct {
+	proxyUrl   string
+	httpClient *http.Client
+}
+
+func (c *goProxyClient) VersionExists(modulePath, pkgVersion string) (bool, error) {
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s/@v/%s.info", c.proxyUrl, url.PathEscape(modulePath), url.PathEscape(pkgVersion)))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+func (c *goProxyClient) ListPublishedVersions(modulePath string) ([]string, error) {
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s/@v/list", c.proxyUrl, url.PathEscape(modulePath)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("go proxy returned status %d for module %s", resp.StatusCode, modulePath)
+	}
+	var versions []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			versions = append(versions, line)
+		}
+	}
+	return versions, scanner.Err()
+}
+
+// IsYanked reports whether pkgVersion was removed from the module's @v/list, which is how the Go proxy reflects a
+// 'retract' directive published in a later version of the module's go.mod.
+func (c *goProxyClient) IsYanked(modulePath, pkgVersion string) (bool, error) {
+	versions, err := c.ListPublishedVersions(modulePath)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		if v == pkgVersion {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type npmPackageMetadata struct {
+	Versions map[string]struct {
+		Deprecated string `json:"deprecated"`
+	} `json:"versions"`
+}
+
+type npmRegistryClient struct {
+	registryUrl string
+	httpClient  *http.Client
+}
+
+func (c *npmRegistryClient) fetchMetadata(packageName string) (*npmPackageMetadata, error) {
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/%s", c.registryUrl, url.PathEscape(packageName)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("npm registry returned status %d for package %s", resp.StatusCode, packageName)
+	}
+	metadata := &npmPackageMetadata{}
+	if err = json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (c *npmRegistryClient) VersionExists(packageName, pkgVersion string) (bool, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return false, err
+	}
+	_, exists := metadata.Versions[pkgVersion]
+	return exists, nil
+}
+
+func (c *npmRegistryClient) ListPublishedVersions(packageName string) ([]string, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(metadata.Versions))
+	for v := range metadata.Versions {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (c *npmRegistryClient) IsYanked(packageName, pkgVersion string) (bool, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return false, err
+	}
+	return metadata.Versions[pkgVersion].Deprecated != "", nil
+}
+
+type pypiPackageMetadata struct {
+	Releases map[string][]struct {
+		Yanked bool `json:"yanked"`
+	} `json:"releases"`
+}
+
+type pypiRegistryClient struct {
+	registryUrl string
+	httpClient  *http.Client
+}
+
+func (c *pypiRegistryClient) fetchMetadata(packageName string) (*pypiPackageMetadata, error) {
+	resp, err := c.httpClient.Get(fmt.Sprintf("%s/pypi/%s/json", c.registryUrl, url.PathEscape(packageName)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("PyPI returned status %d for package %s", resp.StatusCode, packageName)
+	}
+	metadata := &pypiPackageMetadata{}
+	if err = json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (c *pypiRegistryClient) VersionExists(packageName, pkgVersion string) (bool, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return false, err
+	}
+	_, exists := metadata.Releases[pkgVersion]
+	return exists, nil
+}
+
+func (c *pypiRegistryClient) ListPublishedVersions(packageName string) ([]string, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(metadata.Releases))
+	for v := range metadata.Releases {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (c *pypiRegistryClient) IsYanked(packageName, pkgVersion string) (bool, error) {
+	metadata, err := c.fetchMetadata(packageName)
+	if err != nil {
+		return false, err
+	}
+	files, exists := metadata.Releases[pkgVersion]
+	if !exists || len(files) == 0 {
+		return false, nil
+	}
+	// A release is only truly yanked once every uploaded file for that version is marked yanked.
+	for _, file := range files {
+		if !file.Yanked {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type mavenMetadata struct {
+	Versioning struct {
+		Versions struct {
+			Version []string `xml:"version"`
+		} `xml:"versions"`
+	} `xml:"versioning"`
+}
+
+type mavenCentralClient struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+// mavenMetadataUrl builds the maven-metadata.xml URL for a groupId:artifactId coordinate such as
+// "com.fasterxml.jackson.core:jackson-databind".
+func (c *mavenCentralClient) mavenMetadataUrl(coordinate string) string {
+	groupId, artifactId := splitMavenCoordinate(coordinate)
+	return fmt.Sprintf("%s/%s/%s/maven-metadata.xml", c.baseUrl, strings.ReplaceAll(groupId, ".", "/"), artifactId)
+}
+
+// splitMavenCoordinate splits a "groupId:artifactId" Xray coordinate into its two parts.
+func splitMavenCoordinate(coordinate string) (groupId, artifactId string) {
+	parts := strings.SplitN(coordinate, ":", 2)
+	if len(parts) != 2 {
+		return coordinate, ""
+	}
+	return parts[0], parts[1]
+}
+
+func (c *mavenCentralClient) fetchMetadata(coordinate string) (*mavenMetadata, error) {
+	resp, err := c.httpClient.Get(c.mavenMetadataUrl(coordinate))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("maven central returned status %d for %s", resp.StatusCode, coordinate)
+	}
+	metadata := &mavenMetadata{}
+	if err = xml.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (c *mavenCentralClient) VersionExists(coordinate, pkgVersion string) (bool, error) {
+	versions, err := c.ListPublishedVersions(coordinate)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		if v == pkgVersion {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *mavenCentralClient) ListPublishedVersions(coordinate string) ([]string, error) {
+	metadata, err := c.fetchMetadata(coordinate)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Versioning.Versions.Version, nil
+}
+
+// IsYanked always reports false for Maven: Maven Central has no retraction mechanism, a published artifact is
+// immutable and can never be removed.
+func (c *mavenCentralClient) IsYanked(string, string) (bool, error) {
+	return false, nil
+}